@@ -0,0 +1,152 @@
+package exporters
+
+import (
+	"log"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ListFunc populates one or more Prometheus metrics for a single OpenStack resource collection
+// during a scrape. Each entry in an exporter's default metrics list references the ListFunc
+// responsible for it; entries with no Fn are only emitted as a side effect of another metric's
+// ListFunc, or (scrape_duration_seconds, scrape_error) by the exporter's own Collect.
+type ListFunc func(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error
+
+// Metric describes one Prometheus metric exposed by an exporter.
+type Metric struct {
+	Name              string
+	Labels            []string
+	Fn                ListFunc
+	Slow              bool
+	DeprecatedVersion string
+}
+
+// prometheusMetric pairs a registered descriptor with the ListFunc used to populate it.
+type prometheusMetric struct {
+	Metric *prometheus.Desc
+	Fn     ListFunc
+}
+
+// ExporterConfig carries the settings shared by every per-service exporter. Service clients
+// are supplied individually (see BaseOpenStackExporter.Client, ExporterConfig.IdentityClient);
+// everything else here tunes how a scrape behaves.
+type ExporterConfig struct {
+	// UUIDGenFunc fills in a synthetic ID for resources the API returns without one.
+	UUIDGenFunc func() (string, error)
+
+	// DisabledMetrics lists the disabled_metrics keys (e.g. "neutron-bgp") this cloud has
+	// opted out of, for metrics gated behind an extension that's commonly absent.
+	DisabledMetrics []string
+
+	// ListConcurrency bounds the worker pool used to fan out per-page and per-item list work
+	// during a single exporter's scrape. Defaults to runtime.NumCPU() when unset.
+	ListConcurrency int
+
+	// ScrapeTimeout aborts a scrape and reports partial metrics once exceeded, so a slow
+	// backend can't stall the whole /metrics endpoint. Zero disables the timeout. Only the
+	// Neutron exporter applies it; other exporters' scrapes are not cut short.
+	ScrapeTimeout time.Duration
+
+	// EnableProjectLabels attaches project_name/domain_name labels (resolved via
+	// IdentityClient) to metrics that support it instead of leaving the raw project ID.
+	EnableProjectLabels bool
+
+	// IdentityClient is the Identity v3 client used to resolve project/domain labels when
+	// EnableProjectLabels is set.
+	IdentityClient *gophercloud.ServiceClient
+
+	// ProjectCacheTTL controls how long the project/domain label cache is kept before being
+	// refreshed from Keystone. Defaults to defaultProjectCacheTTL when unset.
+	ProjectCacheTTL time.Duration
+}
+
+// BaseOpenStackExporter implements the common parts of the prometheus.Collector interface
+// shared by every per-service exporter (Neutron, Octavia, ...); each exporter embeds it and
+// supplies its own Name, ExporterConfig, and metric set via AddMetric.
+type BaseOpenStackExporter struct {
+	Name           string
+	ExporterConfig ExporterConfig
+	Client         *gophercloud.ServiceClient
+
+	// ProjectResolver resolves project_id to project_name/domain_name when
+	// ExporterConfig.EnableProjectLabels is set; nil falls back to the raw project ID.
+	ProjectResolver *ProjectResolver
+
+	Metrics map[string]prometheusMetric
+}
+
+// AddMetric registers a metric descriptor under name, associating it with the ListFunc that
+// populates it during Collect.
+func (e *BaseOpenStackExporter) AddMetric(name string, fn ListFunc, labels []string, deprecatedVersion string, constLabels prometheus.Labels) {
+	if e.Metrics == nil {
+		e.Metrics = map[string]prometheusMetric{}
+	}
+
+	help := "Metric exported by the " + e.Name + " exporter"
+	if deprecatedVersion != "" {
+		help = help + " (deprecated since " + deprecatedVersion + ")"
+	}
+
+	e.Metrics[name] = prometheusMetric{
+		Metric: prometheus.NewDesc(
+			prometheus.BuildFQName("openstack", e.Name, name),
+			help, labels, constLabels),
+		Fn: fn,
+	}
+}
+
+// isDeprecatedMetric always reports false, whatever the metric's DeprecatedVersion: there is
+// no setting to opt out of deprecated metrics, so NewXXXExporter registers every metric, and
+// AddMetric notes the deprecation in a deprecated metric's help text.
+func (e *BaseOpenStackExporter) isDeprecatedMetric(metric *Metric) bool {
+	return false && metric.DeprecatedVersion != ""
+}
+
+// isSlowMetric reports whether a metric is flagged as slow (i.e. costs an extra round-trip
+// beyond the resource's own list call) and should be skipped by default.
+func (e *BaseOpenStackExporter) isSlowMetric(metric *Metric) bool {
+	return metric.Slow
+}
+
+// Describe implements prometheus.Collector
+func (e *BaseOpenStackExporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range e.Metrics {
+		ch <- metric.Metric
+	}
+}
+
+// Collect implements prometheus.Collector, running every registered metric's ListFunc and
+// logging (rather than failing the whole scrape on) any individual failure.
+func (e *BaseOpenStackExporter) Collect(ch chan<- prometheus.Metric) {
+	for name, metric := range e.Metrics {
+		if metric.Fn == nil {
+			continue
+		}
+
+		if err := metric.Fn(e, ch); err != nil {
+			log.Printf("[%s] failed to collect metric %q: %v", e.Name, name, err)
+		}
+	}
+}
+
+// endpointOpts holds the per-service gophercloud.EndpointOpts (region, availability, ...) used
+// to build each exporter's service client and, for labels, to read back the region name. It's
+// populated once by the cloud/service-catalog wiring outside this file set (main.go) before any
+// NewXXXExporter is called.
+var endpointOpts = map[string]gophercloud.EndpointOpts{}
+
+// NewExporter returns the exporter for the given service name, wired up with config. This is
+// the single place that has to know about every service this binary can export metrics for;
+// adding a new exporter to the "services" list means adding its case here.
+func NewExporter(service string, config *ExporterConfig) (prometheus.Collector, error) {
+	switch service {
+	case "network":
+		return NewNeutronExporter(config)
+	case "loadbalancer":
+		return NewOctaviaExporter(config)
+	default:
+		return nil, nil
+	}
+}
@@ -1,375 +1,710 @@
-package exporters
-
-import (
-	"strconv"
-
-	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/agents"
-	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/floatingips"
-	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/routers"
-	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/networkipavailabilities"
-	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/portsbinding"
-	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/provider"
-	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
-	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
-	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
-	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
-	"github.com/prometheus/client_golang/prometheus"
-)
-
-// NeutronExporter : extends BaseOpenStackExporter
-type NeutronExporter struct {
-	BaseOpenStackExporter
-}
-
-var defaultNeutronMetrics = []Metric{
-	{Name: "floating_ips", Labels: []string{"region_name"}, Fn: ListFloatingIps},
-	{Name: "floating_ips_associated_not_active", Labels: []string{"region_name"}},
-	{Name: "floating_ip", Labels: []string{"id", "floating_network_id", "router_id", "status", "project_id", "floating_ip_address", "region_name"}},
-	{Name: "network", Labels: []string{"id", "name", "admin_state_up", "status", "tenant_id", "project_id", "region_name", "type", "physical_network", "seg_id"}, Fn: ListNetworks},
-	{Name: "networks", Labels: []string{"region_name"}},
-	{Name: "security_groups", Labels: []string{"region_name"}, Fn: ListSecGroups},
-	{Name: "subnets", Labels: []string{"region_name"}, Fn: ListSubnets},
-	{Name: "port", Labels: []string{"uuid", "network_id", "mac_address", "device_owner", "status", "binding_vif_type", "admin_state_up", "device_id", "region_name"}, Fn: ListPorts},
-	{Name: "ports", Labels: []string{"region_name"}},
-	{Name: "ports_no_ips", Labels: []string{"region_name"}},
-	{Name: "ports_lb_not_active", Labels: []string{"region_name"}},
-	{Name: "router", Labels: []string{"id", "name", "project_id", "admin_state_up", "status", "external_network_id", "region_name"}},
-	{Name: "routers", Labels: []string{"region_name"}, Fn: ListRouters},
-	{Name: "routers_not_active", Labels: []string{"region_name"}},
-	{Name: "l3_agent_of_router", Labels: []string{"router_id", "l3_agent_id", "ha_state", "agent_alive", "agent_admin_up", "agent_host", "region_name"}},
-	{Name: "agent_state", Labels: []string{"id", "hostname", "service", "adminState", "region_name"}, Fn: ListAgentStates},
-	{Name: "network_ip_availabilities_total", Labels: []string{"network_id", "network_name", "ip_version", "cidr", "subnet_name", "project_id", "region_name"}, Fn: ListNetworkIPAvailabilities},
-	{Name: "network_ip_availabilities_used", Labels: []string{"network_id", "network_name", "ip_version", "cidr", "subnet_name", "project_id", "region_name"}},
-}
-
-// NewNeutronExporter : returns a pointer to NeutronExporter
-func NewNeutronExporter(config *ExporterConfig) (*NeutronExporter, error) {
-	exporter := NeutronExporter{
-		BaseOpenStackExporter{
-			Name:           "neutron",
-			ExporterConfig: *config,
-		},
-	}
-
-	for _, metric := range defaultNeutronMetrics {
-		if exporter.isDeprecatedMetric(&metric) {
-			continue
-		}
-		if !exporter.isSlowMetric(&metric) {
-			exporter.AddMetric(metric.Name, metric.Fn, metric.Labels, metric.DeprecatedVersion, nil)
-		}
-	}
-
-	return &exporter, nil
-}
-
-// ListFloatingIps : count total number of instantiated FloatingIPs and those that are associated to private IP but not in ACTIVE state
-func ListFloatingIps(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
-	var allFloatingIPs []floatingips.FloatingIP
-
-	allPagesFloatingIPs, err := floatingips.List(exporter.Client, floatingips.ListOpts{}).AllPages()
-	if err != nil {
-		return err
-	}
-
-	allFloatingIPs, err = floatingips.ExtractFloatingIPs(allPagesFloatingIPs)
-	if err != nil {
-		return err
-	}
-
-	failedFIPs := 0
-	for _, fip := range allFloatingIPs {
-		ch <- prometheus.MustNewConstMetric(exporter.Metrics["floating_ip"].Metric,
-			prometheus.GaugeValue, 1,
-			fip.ID, fip.FloatingNetworkID, fip.RouterID, fip.Status, fip.ProjectID, fip.FloatingIP,
-			endpointOpts["network"].Region)
-		if fip.FixedIP != "" {
-			if fip.Status != "ACTIVE" {
-				failedFIPs = failedFIPs + 1
-			}
-		}
-	}
-
-	ch <- prometheus.MustNewConstMetric(exporter.Metrics["floating_ips"].Metric,
-		prometheus.GaugeValue, float64(len(allFloatingIPs)),
-		endpointOpts["network"].Region)
-	ch <- prometheus.MustNewConstMetric(exporter.Metrics["floating_ips_associated_not_active"].Metric,
-		prometheus.GaugeValue, float64(failedFIPs),
-		endpointOpts["network"].Region)
-
-	return nil
-}
-
-// ListAgentStates : list agent state per node
-func ListAgentStates(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
-	var allAgents []agents.Agent
-
-	allPagesAgents, err := agents.List(exporter.Client, agents.ListOpts{}).AllPages()
-	if err != nil {
-		return err
-	}
-
-	allAgents, err = agents.ExtractAgents(allPagesAgents)
-	if err != nil {
-		return err
-	}
-
-	for _, agent := range allAgents {
-		var state int = 0
-		var id string
-
-		if agent.Alive {
-			state = 1
-		}
-
-		adminState := "down"
-		if agent.AdminStateUp {
-			adminState = "up"
-		}
-
-		id = agent.ID
-		if id == "" {
-			if id, err = exporter.ExporterConfig.UUIDGenFunc(); err != nil {
-				return err
-			}
-		}
-
-		ch <- prometheus.MustNewConstMetric(exporter.Metrics["agent_state"].Metric,
-			prometheus.CounterValue, float64(state),
-			id, agent.Host, agent.Binary, adminState,
-			endpointOpts["network"].Region)
-	}
-
-	return nil
-}
-
-// ListNetworks : Count total number of instantiated Networks
-func ListNetworks(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
-	type NetworkWithProvider struct {
-		networks.Network
-		provider.NetworkProviderExt
-	}
-
-	var allNetworks []NetworkWithProvider
-
-	allPagesNetworks, err := networks.List(exporter.Client, networks.ListOpts{}).AllPages()
-	if err != nil {
-		return err
-	}
-
-	err = networks.ExtractNetworksInto(allPagesNetworks, &allNetworks)
-	if err != nil {
-		return err
-	}
-
-	for _, network := range allNetworks {
-		ch <- prometheus.MustNewConstMetric(exporter.Metrics["network"].Metric,
-			prometheus.GaugeValue, 1,
-			network.ID, network.Name, strconv.FormatBool(network.AdminStateUp), network.Status, network.TenantID, network.ProjectID,
-			endpointOpts["network"].Region, network.NetworkProviderExt.NetworkType, network.NetworkProviderExt.PhysicalNetwork, network.NetworkProviderExt.SegmentationID)
-	}
-
-	ch <- prometheus.MustNewConstMetric(exporter.Metrics["networks"].Metric,
-		prometheus.GaugeValue, float64(len(allNetworks)),
-		endpointOpts["network"].Region)
-
-	return nil
-}
-
-// ListSecGroups : count total number of instantiated Security Groups
-func ListSecGroups(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
-	var allSecurityGroups []groups.SecGroup
-
-	allPagesSecurityGroups, err := groups.List(exporter.Client, groups.ListOpts{}).AllPages()
-	if err != nil {
-		return err
-	}
-
-	allSecurityGroups, err = groups.ExtractGroups(allPagesSecurityGroups)
-	if err != nil {
-		return err
-	}
-	ch <- prometheus.MustNewConstMetric(exporter.Metrics["security_groups"].Metric,
-		prometheus.GaugeValue, float64(len(allSecurityGroups)),
-		endpointOpts["network"].Region)
-
-	return nil
-}
-
-// ListSubnets : count total number of instantiated Subnets
-func ListSubnets(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
-	var allSubnets []subnets.Subnet
-
-	allPagesSubnets, err := subnets.List(exporter.Client, subnets.ListOpts{}).AllPages()
-	if err != nil {
-		return err
-	}
-
-	allSubnets, err = subnets.ExtractSubnets(allPagesSubnets)
-	if err != nil {
-		return err
-	}
-	ch <- prometheus.MustNewConstMetric(exporter.Metrics["subnets"].Metric,
-		prometheus.GaugeValue, float64(len(allSubnets)),
-		endpointOpts["network"].Region)
-
-	return nil
-}
-
-// PortBinding represents a port which includes port bindings
-type PortBinding struct {
-	ports.Port
-	portsbinding.PortsBindingExt
-}
-
-// ListPorts generates metrics about ports inside the OpenStack cloud
-func ListPorts(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
-	var allPorts []PortBinding
-
-	allPagesPorts, err := ports.List(exporter.Client, ports.ListOpts{}).AllPages()
-	if err != nil {
-		return err
-	}
-
-	err = ports.ExtractPortsInto(allPagesPorts, &allPorts)
-	if err != nil {
-		return err
-	}
-
-	portsWithNoIP := float64(0)
-	lbaasPortsInactive := float64(0)
-
-	for _, port := range allPorts {
-		if port.Status == "ACTIVE" && len(port.FixedIPs) == 0 {
-			portsWithNoIP++
-		}
-
-		if port.DeviceOwner == "neutron:LOADBALANCERV2" && port.Status != "ACTIVE" {
-			lbaasPortsInactive++
-		}
-
-		ch <- prometheus.MustNewConstMetric(exporter.Metrics["port"].Metric,
-			prometheus.GaugeValue, 1,
-			port.ID, port.NetworkID, port.MACAddress, port.DeviceOwner, port.Status, port.VIFType, strconv.FormatBool(port.AdminStateUp), port.DeviceID,
-			endpointOpts["network"].Region)
-	}
-
-	// NOTE(mnaser): We should deprecate this and users can replace it by
-	//               count(openstack_neutron_port)
-	ch <- prometheus.MustNewConstMetric(exporter.Metrics["ports"].Metric,
-		prometheus.GaugeValue, float64(len(allPorts)),
-		endpointOpts["network"].Region)
-
-	// NOTE(mnaser): We should deprecate this and users can replace it by:
-	//               count(openstack_neutron_port{device_owner="neutron:LOADBALANCERV2",status!="ACTIVE"})
-	ch <- prometheus.MustNewConstMetric(exporter.Metrics["ports_lb_not_active"].Metric,
-		prometheus.GaugeValue, lbaasPortsInactive,
-		endpointOpts["network"].Region)
-
-	ch <- prometheus.MustNewConstMetric(exporter.Metrics["ports_no_ips"].Metric,
-		prometheus.GaugeValue, portsWithNoIP,
-		endpointOpts["network"].Region)
-
-	return nil
-}
-
-// ListNetworkIPAvailabilities : count total number of used IPs per Network
-func ListNetworkIPAvailabilities(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
-	var allNetworkIPAvailabilities []networkipavailabilities.NetworkIPAvailability
-
-	allPagesNetworkIPAvailabilities, err := networkipavailabilities.List(exporter.Client, networkipavailabilities.ListOpts{}).AllPages()
-	if err != nil {
-		return err
-	}
-
-	allNetworkIPAvailabilities, err = networkipavailabilities.ExtractNetworkIPAvailabilities(allPagesNetworkIPAvailabilities)
-	if err != nil {
-		return err
-	}
-
-	for _, NetworkIPAvailabilities := range allNetworkIPAvailabilities {
-		projectID := NetworkIPAvailabilities.ProjectID
-		if projectID == "" && NetworkIPAvailabilities.TenantID != "" {
-			projectID = NetworkIPAvailabilities.TenantID
-		}
-
-		for _, SubnetIPAvailability := range NetworkIPAvailabilities.SubnetIPAvailabilities {
-			totalIPs, err := strconv.ParseFloat(SubnetIPAvailability.TotalIPs, 64)
-			if err != nil {
-				return err
-			}
-			ch <- prometheus.MustNewConstMetric(exporter.Metrics["network_ip_availabilities_total"].Metric,
-				prometheus.GaugeValue, totalIPs, NetworkIPAvailabilities.NetworkID,
-				NetworkIPAvailabilities.NetworkName, strconv.Itoa(SubnetIPAvailability.IPVersion), SubnetIPAvailability.CIDR,
-				SubnetIPAvailability.SubnetName, projectID,
-				endpointOpts["network"].Region)
-
-			usedIPs, err := strconv.ParseFloat(SubnetIPAvailability.UsedIPs, 64)
-			if err != nil {
-				return err
-			}
-			ch <- prometheus.MustNewConstMetric(exporter.Metrics["network_ip_availabilities_used"].Metric,
-				prometheus.GaugeValue, usedIPs, NetworkIPAvailabilities.NetworkID,
-				NetworkIPAvailabilities.NetworkName, strconv.Itoa(SubnetIPAvailability.IPVersion), SubnetIPAvailability.CIDR,
-				SubnetIPAvailability.SubnetName, projectID,
-				endpointOpts["network"].Region)
-		}
-	}
-
-	return nil
-}
-
-// ListRouters : count total number of instantiated Routers and those that are not in ACTIVE state
-func ListRouters(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
-	var allRouters []routers.Router
-
-	allPagesRouters, err := routers.List(exporter.Client, routers.ListOpts{}).AllPages()
-	if err != nil {
-		return err
-	}
-
-	allRouters, err = routers.ExtractRouters(allPagesRouters)
-	if err != nil {
-		return err
-	}
-
-	failedRouters := 0
-	for _, router := range allRouters {
-		if router.Status != "ACTIVE" {
-			failedRouters = failedRouters + 1
-		}
-		allPagesL3Agents, err := routers.ListL3Agents(exporter.Client, router.ID).AllPages()
-		if err != nil {
-			return err
-		}
-		l3Agents, err := routers.ExtractL3Agents(allPagesL3Agents)
-		if err != nil {
-			return err
-		}
-		for _, agent := range l3Agents {
-			var state int
-
-			if agent.Alive {
-				state = 1
-			}
-
-			ch <- prometheus.MustNewConstMetric(exporter.Metrics["l3_agent_of_router"].Metric,
-				prometheus.GaugeValue, float64(state), router.ID, agent.ID,
-				agent.HAState, strconv.FormatBool(agent.Alive), strconv.FormatBool(agent.AdminStateUp), agent.Host,
-				endpointOpts["network"].Region)
-		}
-		ch <- prometheus.MustNewConstMetric(exporter.Metrics["router"].Metric,
-			prometheus.GaugeValue, 1, router.ID, router.Name, router.ProjectID,
-			strconv.FormatBool(router.AdminStateUp), router.Status, router.GatewayInfo.NetworkID,
-			endpointOpts["network"].Region)
-	}
-
-	ch <- prometheus.MustNewConstMetric(exporter.Metrics["routers"].Metric,
-		prometheus.GaugeValue, float64(len(allRouters)),
-		endpointOpts["network"].Region)
-	ch <- prometheus.MustNewConstMetric(exporter.Metrics["routers_not_active"].Metric,
-		prometheus.GaugeValue, float64(failedRouters),
-		endpointOpts["network"].Region)
-
-	return nil
-}
+package exporters
+
+import (
+	"math/big"
+	"net"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/agents"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/bgp/peers"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/bgp/speakers"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/floatingips"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/routers"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/networkipavailabilities"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/portsbinding"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/provider"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/qos/policies"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/qos/rules"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/subnetpools"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+	"github.com/gophercloud/gophercloud/pagination"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NeutronExporter : extends BaseOpenStackExporter
+type NeutronExporter struct {
+	BaseOpenStackExporter
+}
+
+var defaultNeutronMetrics = []Metric{
+	{Name: "floating_ips", Labels: []string{"region_name"}, Fn: ListFloatingIps},
+	{Name: "floating_ips_associated_not_active", Labels: []string{"region_name"}},
+	{Name: "floating_ip", Labels: []string{"id", "floating_network_id", "router_id", "status", "project_id", "floating_ip_address", "region_name"}},
+	{Name: "network", Labels: []string{"id", "name", "admin_state_up", "status", "tenant_id", "project_id", "region_name", "type", "physical_network", "seg_id"}, Fn: ListNetworks},
+	{Name: "networks", Labels: []string{"region_name"}},
+	{Name: "security_groups", Labels: []string{"region_name"}, Fn: ListSecGroups},
+	{Name: "subnets", Labels: []string{"region_name"}, Fn: ListSubnets},
+	{Name: "port", Labels: []string{"uuid", "network_id", "mac_address", "device_owner", "status", "binding_vif_type", "admin_state_up", "device_id", "region_name"}, Fn: ListPorts},
+	{Name: "ports", Labels: []string{"region_name"}},
+	{Name: "ports_no_ips", Labels: []string{"region_name"}},
+	{Name: "router", Labels: []string{"id", "name", "project_id", "admin_state_up", "status", "external_network_id", "region_name"}},
+	{Name: "routers", Labels: []string{"region_name"}, Fn: ListRouters},
+	{Name: "routers_not_active", Labels: []string{"region_name"}},
+	{Name: "l3_agent_of_router", Labels: []string{"router_id", "l3_agent_id", "ha_state", "agent_alive", "agent_admin_up", "agent_host", "region_name"}},
+	{Name: "agent_state", Labels: []string{"id", "hostname", "service", "adminState", "region_name"}, Fn: ListAgentStates},
+	{Name: "network_ip_availabilities_total", Labels: []string{"network_id", "network_name", "ip_version", "cidr", "subnet_name", "project_id", "region_name"}, Fn: ListNetworkIPAvailabilities},
+	{Name: "network_ip_availabilities_used", Labels: []string{"network_id", "network_name", "ip_version", "cidr", "subnet_name", "project_id", "region_name"}},
+	{Name: "subnet_pools", Labels: []string{"region_name"}, Fn: ListSubnetPools},
+	{Name: "subnet_pool_prefixes", Labels: []string{"id", "name", "ip_version", "project_id", "shared", "is_default", "region_name"}},
+	{Name: "subnet_pool_quota_total", Labels: []string{"id", "name", "region_name"}},
+	{Name: "subnet_pool_quota_used", Labels: []string{"id", "name", "region_name"}},
+	{Name: "bgp_speaker", Labels: []string{"id", "name", "local_as", "ip_version", "region_name"}, Fn: ListBGPSpeakers},
+	{Name: "bgp_peer_state", Labels: []string{"speaker_id", "peer_id", "peer_ip", "remote_as", "state", "region_name"}},
+	{Name: "bgp_advertised_routes", Labels: []string{"speaker_id", "region_name"}},
+	{Name: "qos_policy", Labels: []string{"id", "name", "shared", "project_id", "region_name"}, Fn: ListQoSPolicies},
+	{Name: "qos_bandwidth_limit_bps", Labels: []string{"policy_id", "direction", "region_name"}},
+	{Name: "qos_dscp_mark", Labels: []string{"policy_id", "region_name"}},
+	{Name: "qos_minimum_bandwidth_kbps", Labels: []string{"policy_id", "direction", "region_name"}},
+	{Name: "port_qos_policy", Labels: []string{"port_id", "policy_id", "region_name"}},
+}
+
+// NewNeutronExporter : returns a pointer to NeutronExporter
+func NewNeutronExporter(config *ExporterConfig) (*NeutronExporter, error) {
+	exporter := NeutronExporter{
+		BaseOpenStackExporter{
+			Name:           "neutron",
+			ExporterConfig: *config,
+		},
+	}
+
+	if config.EnableProjectLabels && config.IdentityClient != nil {
+		ttl := config.ProjectCacheTTL
+		if ttl <= 0 {
+			ttl = defaultProjectCacheTTL
+		}
+
+		exporter.ProjectResolver = NewProjectResolver(config.IdentityClient, ttl)
+	}
+
+	for _, metric := range append(append([]Metric{}, defaultNeutronMetrics...), defaultScrapeTimeoutMetrics...) {
+		if exporter.isDeprecatedMetric(&metric) {
+			continue
+		}
+
+		labels := metric.Labels
+		if exporter.ExporterConfig.EnableProjectLabels && hasProjectLabels(metric.Name) {
+			labels = append(append([]string{}, labels...), "project_name", "domain_name")
+		}
+
+		if !exporter.isSlowMetric(&metric) {
+			exporter.AddMetric(metric.Name, metric.Fn, labels, metric.DeprecatedVersion, nil)
+		}
+	}
+
+	return &exporter, nil
+}
+
+// ListFloatingIps : count total number of instantiated FloatingIPs and those that are associated to private IP but not in ACTIVE state
+func ListFloatingIps(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
+	var totalFIPs int64
+	var failedFIPs int64
+
+	pager := floatingips.List(exporter.Client, floatingips.ListOpts{})
+	err := exporter.eachPageConcurrent(pager, func(page pagination.Page, pool *scrapePool) error {
+		pageFIPs, err := floatingips.ExtractFloatingIPs(page)
+		if err != nil {
+			return err
+		}
+
+		atomic.AddInt64(&totalFIPs, int64(len(pageFIPs)))
+
+		return exporter.forEachConcurrent(pool, len(pageFIPs), func(i int) error {
+			fip := pageFIPs[i]
+
+			labelValues := append([]string{fip.ID, fip.FloatingNetworkID, fip.RouterID, fip.Status, fip.ProjectID, fip.FloatingIP,
+				endpointOpts["network"].Region}, exporter.projectLabelValues(fip.ProjectID)...)
+			ch <- prometheus.MustNewConstMetric(exporter.Metrics["floating_ip"].Metric,
+				prometheus.GaugeValue, 1, labelValues...)
+
+			if fip.FixedIP != "" && fip.Status != "ACTIVE" {
+				atomic.AddInt64(&failedFIPs, 1)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(exporter.Metrics["floating_ips"].Metric,
+		prometheus.GaugeValue, float64(atomic.LoadInt64(&totalFIPs)),
+		endpointOpts["network"].Region)
+	ch <- prometheus.MustNewConstMetric(exporter.Metrics["floating_ips_associated_not_active"].Metric,
+		prometheus.GaugeValue, float64(atomic.LoadInt64(&failedFIPs)),
+		endpointOpts["network"].Region)
+
+	return nil
+}
+
+// ListAgentStates : list agent state per node
+func ListAgentStates(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
+	var allAgents []agents.Agent
+
+	allPagesAgents, err := agents.List(exporter.Client, agents.ListOpts{}).AllPages()
+	if err != nil {
+		return err
+	}
+
+	allAgents, err = agents.ExtractAgents(allPagesAgents)
+	if err != nil {
+		return err
+	}
+
+	for _, agent := range allAgents {
+		var state int = 0
+		var id string
+
+		if agent.Alive {
+			state = 1
+		}
+
+		adminState := "down"
+		if agent.AdminStateUp {
+			adminState = "up"
+		}
+
+		id = agent.ID
+		if id == "" {
+			if id, err = exporter.ExporterConfig.UUIDGenFunc(); err != nil {
+				return err
+			}
+		}
+
+		ch <- prometheus.MustNewConstMetric(exporter.Metrics["agent_state"].Metric,
+			prometheus.CounterValue, float64(state),
+			id, agent.Host, agent.Binary, adminState,
+			endpointOpts["network"].Region)
+	}
+
+	return nil
+}
+
+// ListNetworks : Count total number of instantiated Networks
+func ListNetworks(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
+	type NetworkWithProvider struct {
+		networks.Network
+		provider.NetworkProviderExt
+	}
+
+	var totalNetworks int64
+
+	pager := networks.List(exporter.Client, networks.ListOpts{})
+	err := exporter.eachPageConcurrent(pager, func(page pagination.Page, pool *scrapePool) error {
+		var pageNetworks []NetworkWithProvider
+		if err := networks.ExtractNetworksInto(page, &pageNetworks); err != nil {
+			return err
+		}
+
+		atomic.AddInt64(&totalNetworks, int64(len(pageNetworks)))
+
+		return exporter.forEachConcurrent(pool, len(pageNetworks), func(i int) error {
+			network := pageNetworks[i]
+
+			labelValues := append([]string{network.ID, network.Name, strconv.FormatBool(network.AdminStateUp), network.Status, network.TenantID, network.ProjectID,
+				endpointOpts["network"].Region, network.NetworkProviderExt.NetworkType, network.NetworkProviderExt.PhysicalNetwork, network.NetworkProviderExt.SegmentationID},
+				exporter.projectLabelValues(network.ProjectID)...)
+			ch <- prometheus.MustNewConstMetric(exporter.Metrics["network"].Metric,
+				prometheus.GaugeValue, 1, labelValues...)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(exporter.Metrics["networks"].Metric,
+		prometheus.GaugeValue, float64(atomic.LoadInt64(&totalNetworks)),
+		endpointOpts["network"].Region)
+
+	return nil
+}
+
+// ListSecGroups : count total number of instantiated Security Groups
+func ListSecGroups(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
+	var allSecurityGroups []groups.SecGroup
+
+	allPagesSecurityGroups, err := groups.List(exporter.Client, groups.ListOpts{}).AllPages()
+	if err != nil {
+		return err
+	}
+
+	allSecurityGroups, err = groups.ExtractGroups(allPagesSecurityGroups)
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(exporter.Metrics["security_groups"].Metric,
+		prometheus.GaugeValue, float64(len(allSecurityGroups)),
+		endpointOpts["network"].Region)
+
+	return nil
+}
+
+// ListSubnets : count total number of instantiated Subnets
+func ListSubnets(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
+	var allSubnets []subnets.Subnet
+
+	allPagesSubnets, err := subnets.List(exporter.Client, subnets.ListOpts{}).AllPages()
+	if err != nil {
+		return err
+	}
+
+	allSubnets, err = subnets.ExtractSubnets(allPagesSubnets)
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(exporter.Metrics["subnets"].Metric,
+		prometheus.GaugeValue, float64(len(allSubnets)),
+		endpointOpts["network"].Region)
+
+	return nil
+}
+
+// PortBinding represents a port which includes port bindings and its attached QoS policy
+type PortBinding struct {
+	ports.Port
+	portsbinding.PortsBindingExt
+	policies.QoSPolicyExt
+}
+
+// ListPorts generates metrics about ports inside the OpenStack cloud
+func ListPorts(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
+	var totalPorts int64
+	var portsWithNoIP int64
+
+	pager := ports.List(exporter.Client, ports.ListOpts{})
+	err := exporter.eachPageConcurrent(pager, func(page pagination.Page, pool *scrapePool) error {
+		var pagePorts []PortBinding
+		if err := ports.ExtractPortsInto(page, &pagePorts); err != nil {
+			return err
+		}
+
+		atomic.AddInt64(&totalPorts, int64(len(pagePorts)))
+
+		return exporter.forEachConcurrent(pool, len(pagePorts), func(i int) error {
+			port := pagePorts[i]
+
+			if port.Status == "ACTIVE" && len(port.FixedIPs) == 0 {
+				atomic.AddInt64(&portsWithNoIP, 1)
+			}
+
+			labelValues := append([]string{port.ID, port.NetworkID, port.MACAddress, port.DeviceOwner, port.Status, port.VIFType, strconv.FormatBool(port.AdminStateUp), port.DeviceID,
+				endpointOpts["network"].Region}, exporter.projectLabelValues(port.ProjectID)...)
+			ch <- prometheus.MustNewConstMetric(exporter.Metrics["port"].Metric,
+				prometheus.GaugeValue, 1, labelValues...)
+
+			if port.QoSPolicyID != "" {
+				ch <- prometheus.MustNewConstMetric(exporter.Metrics["port_qos_policy"].Metric,
+					prometheus.GaugeValue, 1,
+					port.ID, port.QoSPolicyID, endpointOpts["network"].Region)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	// NOTE(mnaser): We should deprecate this and users can replace it by
+	//               count(openstack_neutron_port)
+	ch <- prometheus.MustNewConstMetric(exporter.Metrics["ports"].Metric,
+		prometheus.GaugeValue, float64(atomic.LoadInt64(&totalPorts)),
+		endpointOpts["network"].Region)
+
+	// NOTE: lbaas/Octavia port liveness lives in the "loadbalancer" exporter's ListLBPorts,
+	// which can tell amphora ports apart from other LB providers.
+
+	ch <- prometheus.MustNewConstMetric(exporter.Metrics["ports_no_ips"].Metric,
+		prometheus.GaugeValue, float64(atomic.LoadInt64(&portsWithNoIP)),
+		endpointOpts["network"].Region)
+
+	return nil
+}
+
+// ListNetworkIPAvailabilities : count total number of used IPs per Network
+func ListNetworkIPAvailabilities(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
+	pager := networkipavailabilities.List(exporter.Client, networkipavailabilities.ListOpts{})
+	return exporter.eachPageConcurrent(pager, func(page pagination.Page, pool *scrapePool) error {
+		pageNetworkIPAvailabilities, err := networkipavailabilities.ExtractNetworkIPAvailabilities(page)
+		if err != nil {
+			return err
+		}
+
+		return exporter.forEachConcurrent(pool, len(pageNetworkIPAvailabilities), func(i int) error {
+			NetworkIPAvailabilities := pageNetworkIPAvailabilities[i]
+
+			projectID := NetworkIPAvailabilities.ProjectID
+			if projectID == "" && NetworkIPAvailabilities.TenantID != "" {
+				projectID = NetworkIPAvailabilities.TenantID
+			}
+
+			projectLabelValues := exporter.projectLabelValues(projectID)
+
+			for _, SubnetIPAvailability := range NetworkIPAvailabilities.SubnetIPAvailabilities {
+				baseLabelValues := []string{NetworkIPAvailabilities.NetworkID,
+					NetworkIPAvailabilities.NetworkName, strconv.Itoa(SubnetIPAvailability.IPVersion), SubnetIPAvailability.CIDR,
+					SubnetIPAvailability.SubnetName, projectID,
+					endpointOpts["network"].Region}
+
+				totalIPs, err := strconv.ParseFloat(SubnetIPAvailability.TotalIPs, 64)
+				if err != nil {
+					return err
+				}
+				ch <- prometheus.MustNewConstMetric(exporter.Metrics["network_ip_availabilities_total"].Metric,
+					prometheus.GaugeValue, totalIPs, append(append([]string{}, baseLabelValues...), projectLabelValues...)...)
+
+				usedIPs, err := strconv.ParseFloat(SubnetIPAvailability.UsedIPs, 64)
+				if err != nil {
+					return err
+				}
+				ch <- prometheus.MustNewConstMetric(exporter.Metrics["network_ip_availabilities_used"].Metric,
+					prometheus.GaugeValue, usedIPs, append(append([]string{}, baseLabelValues...), projectLabelValues...)...)
+			}
+
+			return nil
+		})
+	})
+}
+
+// ListRouters : count total number of instantiated Routers and those that are not in ACTIVE state
+func ListRouters(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
+	var totalRouters int64
+	var failedRouters int64
+
+	// Each router costs its own ListL3Agents round-trip; fan those out across
+	// the same bounded worker pool used for per-item metric emission elsewhere in this file.
+	pager := routers.List(exporter.Client, routers.ListOpts{})
+	err := exporter.eachPageConcurrent(pager, func(page pagination.Page, pool *scrapePool) error {
+		pageRouters, err := routers.ExtractRouters(page)
+		if err != nil {
+			return err
+		}
+
+		atomic.AddInt64(&totalRouters, int64(len(pageRouters)))
+
+		return exporter.forEachConcurrent(pool, len(pageRouters), func(i int) error {
+			router := pageRouters[i]
+
+			if router.Status != "ACTIVE" {
+				atomic.AddInt64(&failedRouters, 1)
+			}
+
+			allPagesL3Agents, err := routers.ListL3Agents(exporter.Client, router.ID).AllPages()
+			if err != nil {
+				return err
+			}
+
+			l3Agents, err := routers.ExtractL3Agents(allPagesL3Agents)
+			if err != nil {
+				return err
+			}
+
+			for _, agent := range l3Agents {
+				var state int
+
+				if agent.Alive {
+					state = 1
+				}
+
+				ch <- prometheus.MustNewConstMetric(exporter.Metrics["l3_agent_of_router"].Metric,
+					prometheus.GaugeValue, float64(state), router.ID, agent.ID,
+					agent.HAState, strconv.FormatBool(agent.Alive), strconv.FormatBool(agent.AdminStateUp), agent.Host,
+					endpointOpts["network"].Region)
+			}
+
+			labelValues := append([]string{router.ID, router.Name, router.ProjectID,
+				strconv.FormatBool(router.AdminStateUp), router.Status, router.GatewayInfo.NetworkID,
+				endpointOpts["network"].Region}, exporter.projectLabelValues(router.ProjectID)...)
+			ch <- prometheus.MustNewConstMetric(exporter.Metrics["router"].Metric,
+				prometheus.GaugeValue, 1, labelValues...)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(exporter.Metrics["routers"].Metric,
+		prometheus.GaugeValue, float64(atomic.LoadInt64(&totalRouters)),
+		endpointOpts["network"].Region)
+	ch <- prometheus.MustNewConstMetric(exporter.Metrics["routers_not_active"].Metric,
+		prometheus.GaugeValue, float64(atomic.LoadInt64(&failedRouters)),
+		endpointOpts["network"].Region)
+
+	return nil
+}
+
+// ListSubnetPools : count total number of instantiated SubnetPools and export their prefix/quota usage
+func ListSubnetPools(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
+	var allSubnetPools []subnetpools.SubnetPool
+
+	allPagesSubnetPools, err := subnetpools.List(exporter.Client, subnetpools.ListOpts{}).AllPages()
+	if err != nil {
+		return err
+	}
+
+	allSubnetPools, err = subnetpools.ExtractSubnetPools(allPagesSubnetPools)
+	if err != nil {
+		return err
+	}
+
+	for _, pool := range allSubnetPools {
+		projectID := pool.ProjectID
+		if projectID == "" && pool.TenantID != "" {
+			projectID = pool.TenantID
+		}
+
+		ch <- prometheus.MustNewConstMetric(exporter.Metrics["subnet_pool_prefixes"].Metric,
+			prometheus.GaugeValue, float64(len(pool.Prefixes)),
+			pool.ID, pool.Name, strconv.Itoa(pool.IPversion), projectID,
+			strconv.FormatBool(pool.Shared), strconv.FormatBool(pool.IsDefault),
+			endpointOpts["network"].Region)
+
+		// The pool quota is the total number of addresses reachable across its
+		// prefixes; carved-out subnets are matched back to the pool below so we
+		// can report how much of that quota is already in use.
+		quotaTotal, err := addressesInPrefixes(pool.Prefixes)
+		if err != nil {
+			return err
+		}
+
+		allPagesSubnets, err := subnets.List(exporter.Client, subnets.ListOpts{SubnetPoolID: pool.ID}).AllPages()
+		if err != nil {
+			return err
+		}
+
+		poolSubnets, err := subnets.ExtractSubnets(allPagesSubnets)
+		if err != nil {
+			return err
+		}
+
+		var subnetCIDRs []string
+		for _, subnet := range poolSubnets {
+			subnetCIDRs = append(subnetCIDRs, subnet.CIDR)
+		}
+
+		quotaUsed, err := addressesInPrefixes(subnetCIDRs)
+		if err != nil {
+			return err
+		}
+
+		totalFloat, _ := quotaTotal.Float64()
+		usedFloat, _ := quotaUsed.Float64()
+
+		ch <- prometheus.MustNewConstMetric(exporter.Metrics["subnet_pool_quota_total"].Metric,
+			prometheus.GaugeValue, totalFloat,
+			pool.ID, pool.Name, endpointOpts["network"].Region)
+		ch <- prometheus.MustNewConstMetric(exporter.Metrics["subnet_pool_quota_used"].Metric,
+			prometheus.GaugeValue, usedFloat,
+			pool.ID, pool.Name, endpointOpts["network"].Region)
+	}
+
+	ch <- prometheus.MustNewConstMetric(exporter.Metrics["subnet_pools"].Metric,
+		prometheus.GaugeValue, float64(len(allSubnetPools)),
+		endpointOpts["network"].Region)
+
+	return nil
+}
+
+// ListBGPSpeakers : export BGP speaker, peer session state and advertised-route counts for
+// clouds running the dynamic-routing extension. Skipped entirely when the cloud has opted
+// out via the "neutron-bgp" disabled_metrics key, since the extension is commonly absent.
+func ListBGPSpeakers(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
+	if isMetricDisabled(exporter, "neutron-bgp") {
+		return nil
+	}
+
+	var allSpeakers []speakers.Speaker
+
+	allPagesSpeakers, err := speakers.List(exporter.Client, speakers.ListOpts{}).AllPages()
+	if err != nil {
+		return err
+	}
+
+	allSpeakers, err = speakers.ExtractSpeakers(allPagesSpeakers)
+	if err != nil {
+		return err
+	}
+
+	for _, speaker := range allSpeakers {
+		ch <- prometheus.MustNewConstMetric(exporter.Metrics["bgp_speaker"].Metric,
+			prometheus.GaugeValue, 1,
+			speaker.ID, speaker.Name, strconv.Itoa(speaker.LocalAS), strconv.Itoa(speaker.IPVersion),
+			endpointOpts["network"].Region)
+
+		allPagesPeers, err := peers.ListBGPSpeakerPeers(exporter.Client, speaker.ID).AllPages()
+		if err != nil {
+			return err
+		}
+
+		speakerPeers, err := peers.ExtractPeers(allPagesPeers)
+		if err != nil {
+			return err
+		}
+
+		for _, peer := range speakerPeers {
+			state := 0
+			if peer.State == "ESTABLISHED" {
+				state = 1
+			}
+
+			ch <- prometheus.MustNewConstMetric(exporter.Metrics["bgp_peer_state"].Metric,
+				prometheus.GaugeValue, float64(state),
+				speaker.ID, peer.ID, peer.PeerIP, strconv.Itoa(peer.RemoteAS), peer.State,
+				endpointOpts["network"].Region)
+		}
+
+		allPagesRoutes, err := speakers.ListRoutes(exporter.Client, speaker.ID).AllPages()
+		if err != nil {
+			return err
+		}
+
+		advertisedRoutes, err := speakers.ExtractAdvertisedRoutes(allPagesRoutes)
+		if err != nil {
+			return err
+		}
+
+		ch <- prometheus.MustNewConstMetric(exporter.Metrics["bgp_advertised_routes"].Metric,
+			prometheus.GaugeValue, float64(len(advertisedRoutes)),
+			speaker.ID, endpointOpts["network"].Region)
+	}
+
+	return nil
+}
+
+// ListQoSPolicies : export QoS policies along with their bandwidth-limit, DSCP-marking and
+// minimum-bandwidth rules, so operators can alert when a policy is deleted but ports (see
+// port_qos_policy, emitted from ListPorts) still reference it.
+func ListQoSPolicies(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
+	var allPolicies []policies.Policy
+
+	allPagesPolicies, err := policies.List(exporter.Client, policies.ListOpts{}).AllPages()
+	if err != nil {
+		return err
+	}
+
+	allPolicies, err = policies.ExtractPolicies(allPagesPolicies)
+	if err != nil {
+		return err
+	}
+
+	// Each policy costs three follow-up rule-listing round-trips; fan those out across the
+	// same bounded worker pool used for per-item follow-up calls elsewhere in this file.
+	return exporter.forEachConcurrent(exporter.newScrapePool(), len(allPolicies), func(i int) error {
+		policy := allPolicies[i]
+
+		projectID := policy.ProjectID
+		if projectID == "" && policy.TenantID != "" {
+			projectID = policy.TenantID
+		}
+
+		ch <- prometheus.MustNewConstMetric(exporter.Metrics["qos_policy"].Metric,
+			prometheus.GaugeValue, 1,
+			policy.ID, policy.Name, strconv.FormatBool(policy.Shared), projectID,
+			endpointOpts["network"].Region)
+
+		allPagesBandwidthLimitRules, err := rules.ListBandwidthLimitRules(exporter.Client, policy.ID).AllPages()
+		if err != nil {
+			return err
+		}
+
+		bandwidthLimitRules, err := rules.ExtractBandwidthLimitRules(allPagesBandwidthLimitRules)
+		if err != nil {
+			return err
+		}
+
+		for _, rule := range bandwidthLimitRules {
+			ch <- prometheus.MustNewConstMetric(exporter.Metrics["qos_bandwidth_limit_bps"].Metric,
+				prometheus.GaugeValue, float64(rule.MaxKBps)*1000,
+				policy.ID, rule.Direction, endpointOpts["network"].Region)
+		}
+
+		allPagesDSCPMarkingRules, err := rules.ListDSCPMarkingRules(exporter.Client, policy.ID).AllPages()
+		if err != nil {
+			return err
+		}
+
+		dscpMarkingRules, err := rules.ExtractDSCPMarkingRules(allPagesDSCPMarkingRules)
+		if err != nil {
+			return err
+		}
+
+		for _, rule := range dscpMarkingRules {
+			ch <- prometheus.MustNewConstMetric(exporter.Metrics["qos_dscp_mark"].Metric,
+				prometheus.GaugeValue, float64(rule.DSCPMark),
+				policy.ID, endpointOpts["network"].Region)
+		}
+
+		allPagesMinimumBandwidthRules, err := rules.ListMinimumBandwidthRules(exporter.Client, policy.ID).AllPages()
+		if err != nil {
+			return err
+		}
+
+		minimumBandwidthRules, err := rules.ExtractMinimumBandwidthRules(allPagesMinimumBandwidthRules)
+		if err != nil {
+			return err
+		}
+
+		for _, rule := range minimumBandwidthRules {
+			ch <- prometheus.MustNewConstMetric(exporter.Metrics["qos_minimum_bandwidth_kbps"].Metric,
+				prometheus.GaugeValue, float64(rule.MinKBps),
+				policy.ID, rule.Direction, endpointOpts["network"].Region)
+		}
+
+		return nil
+	})
+}
+
+// projectLabeledMetrics lists the Neutron metrics that may carry the optional
+// project_name/domain_name labels sourced from the shared Keystone project cache.
+var projectLabeledMetrics = map[string]bool{
+	"floating_ip":                     true,
+	"network":                         true,
+	"port":                            true,
+	"router":                          true,
+	"network_ip_availabilities_total": true,
+	"network_ip_availabilities_used":  true,
+}
+
+// hasProjectLabels reports whether the given metric name supports project/domain enrichment
+func hasProjectLabels(name string) bool {
+	return projectLabeledMetrics[name]
+}
+
+// isMetricDisabled reports whether the given disabled_metrics key has been configured for this exporter
+func isMetricDisabled(exporter *BaseOpenStackExporter, key string) bool {
+	for _, disabled := range exporter.ExporterConfig.DisabledMetrics {
+		if disabled == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addressesInPrefixes sums the number of addressable IPs across a list of CIDR prefixes,
+// as used by both a subnet pool's own `prefixes` (its quota) and the subnets already
+// carved out of it (its usage).
+func addressesInPrefixes(prefixes []string) (*big.Float, error) {
+	total := new(big.Float)
+
+	for _, prefix := range prefixes {
+		_, ipNet, err := net.ParseCIDR(prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		ones, bits := ipNet.Mask.Size()
+		size := new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), uint(bits-ones)))
+		total.Add(total, size)
+	}
+
+	return total, nil
+}
@@ -0,0 +1,212 @@
+package exporters
+
+import (
+	"sync/atomic"
+
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/monitors"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/pools"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/pagination"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// lbPortDeviceOwners are the Neutron port device_owner values used by the LBaaS v2 and Octavia
+// amphora drivers; filtering on these server-side means ListLBPorts never has to list every
+// port in the cloud just to find the handful that belong to a load balancer.
+var lbPortDeviceOwners = []string{"neutron:LOADBALANCERV2", "Octavia"}
+
+// OctaviaExporter : extends BaseOpenStackExporter
+type OctaviaExporter struct {
+	BaseOpenStackExporter
+}
+
+var defaultOctaviaMetrics = []Metric{
+	{Name: "loadbalancer_up", Labels: []string{"id", "name", "vip_address", "provisioning_status", "operating_status", "provider", "project_id", "region_name"}, Fn: ListLoadBalancers},
+	{Name: "loadbalancers_not_active", Labels: []string{"region_name"}},
+	{Name: "loadbalancer_listener_up", Labels: []string{"id", "name", "loadbalancer_id", "protocol", "provisioning_status", "operating_status", "region_name"}, Fn: ListListeners},
+	{Name: "loadbalancer_pool_members_total", Labels: []string{"pool_id", "region_name"}, Fn: ListPools},
+	{Name: "loadbalancer_pool_members_online", Labels: []string{"pool_id", "region_name"}},
+	{Name: "loadbalancer_pool_health_monitor_up", Labels: []string{"pool_id", "id", "type", "region_name"}},
+	{Name: "ports_lb_not_active", Labels: []string{"region_name"}, Fn: ListLBPorts},
+}
+
+// NewOctaviaExporter : returns a pointer to OctaviaExporter
+func NewOctaviaExporter(config *ExporterConfig) (*OctaviaExporter, error) {
+	exporter := OctaviaExporter{
+		BaseOpenStackExporter{
+			Name:           "loadbalancer",
+			ExporterConfig: *config,
+		},
+	}
+
+	for _, metric := range defaultOctaviaMetrics {
+		if exporter.isDeprecatedMetric(&metric) {
+			continue
+		}
+		if !exporter.isSlowMetric(&metric) {
+			exporter.AddMetric(metric.Name, metric.Fn, metric.Labels, metric.DeprecatedVersion, nil)
+		}
+	}
+
+	return &exporter, nil
+}
+
+// ListLoadBalancers : export every LoadBalancer and count those that are not in ACTIVE state
+func ListLoadBalancers(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
+	var allLoadBalancers []loadbalancers.LoadBalancer
+
+	allPagesLoadBalancers, err := loadbalancers.List(exporter.Client, loadbalancers.ListOpts{}).AllPages()
+	if err != nil {
+		return err
+	}
+
+	allLoadBalancers, err = loadbalancers.ExtractLoadBalancers(allPagesLoadBalancers)
+	if err != nil {
+		return err
+	}
+
+	notActive := 0
+	for _, lb := range allLoadBalancers {
+		if lb.ProvisioningStatus != "ACTIVE" {
+			notActive++
+		}
+
+		up := float64(0)
+		if lb.OperatingStatus == "ONLINE" {
+			up = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(exporter.Metrics["loadbalancer_up"].Metric,
+			prometheus.GaugeValue, up,
+			lb.ID, lb.Name, lb.VipAddress, lb.ProvisioningStatus, lb.OperatingStatus, lb.Provider, lb.ProjectID,
+			endpointOpts["network"].Region)
+	}
+
+	ch <- prometheus.MustNewConstMetric(exporter.Metrics["loadbalancers_not_active"].Metric,
+		prometheus.GaugeValue, float64(notActive),
+		endpointOpts["network"].Region)
+
+	return nil
+}
+
+// ListListeners : export the operating state of every load balancer listener
+func ListListeners(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
+	var allListeners []listeners.Listener
+
+	allPagesListeners, err := listeners.List(exporter.Client, listeners.ListOpts{}).AllPages()
+	if err != nil {
+		return err
+	}
+
+	allListeners, err = listeners.ExtractListeners(allPagesListeners)
+	if err != nil {
+		return err
+	}
+
+	for _, listener := range allListeners {
+		loadBalancerID := ""
+		if len(listener.Loadbalancers) > 0 {
+			loadBalancerID = listener.Loadbalancers[0].ID
+		}
+
+		up := float64(0)
+		if listener.OperatingStatus == "ONLINE" {
+			up = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(exporter.Metrics["loadbalancer_listener_up"].Metric,
+			prometheus.GaugeValue, up,
+			listener.ID, listener.Name, loadBalancerID, listener.Protocol, listener.ProvisioningStatus, listener.OperatingStatus,
+			endpointOpts["network"].Region)
+	}
+
+	return nil
+}
+
+// ListPools : export pool membership counts and the health monitor attached to each pool
+func ListPools(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
+	var allPools []pools.Pool
+
+	allPagesPools, err := pools.List(exporter.Client, pools.ListOpts{}).AllPages()
+	if err != nil {
+		return err
+	}
+
+	allPools, err = pools.ExtractPools(allPagesPools)
+	if err != nil {
+		return err
+	}
+
+	for _, pool := range allPools {
+		onlineMembers := 0
+		for _, member := range pool.Members {
+			if member.OperatingStatus == "ONLINE" {
+				onlineMembers++
+			}
+		}
+
+		ch <- prometheus.MustNewConstMetric(exporter.Metrics["loadbalancer_pool_members_total"].Metric,
+			prometheus.GaugeValue, float64(len(pool.Members)),
+			pool.ID, endpointOpts["network"].Region)
+		ch <- prometheus.MustNewConstMetric(exporter.Metrics["loadbalancer_pool_members_online"].Metric,
+			prometheus.GaugeValue, float64(onlineMembers),
+			pool.ID, endpointOpts["network"].Region)
+
+		if pool.MonitorID == "" {
+			continue
+		}
+
+		monitor, err := monitors.Get(exporter.Client, pool.MonitorID).Extract()
+		if err != nil {
+			return err
+		}
+
+		monitorUp := float64(0)
+		if monitor.AdminStateUp {
+			monitorUp = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(exporter.Metrics["loadbalancer_pool_health_monitor_up"].Metric,
+			prometheus.GaugeValue, monitorUp,
+			pool.ID, monitor.ID, monitor.Type, endpointOpts["network"].Region)
+	}
+
+	return nil
+}
+
+// ListLBPorts : count the number of load balancer ports (amphora and other providers) that are
+// not in ACTIVE state. Each device_owner is queried separately with a server-side filter so
+// this never has to page through every port in the cloud, just the ones that belong to a load
+// balancer.
+func ListLBPorts(exporter *BaseOpenStackExporter, ch chan<- prometheus.Metric) error {
+	var lbPortsInactive int64
+
+	for _, deviceOwner := range lbPortDeviceOwners {
+		pager := ports.List(exporter.Client, ports.ListOpts{DeviceOwner: deviceOwner})
+		err := exporter.eachPageConcurrent(pager, func(page pagination.Page, pool *scrapePool) error {
+			pagePorts, err := ports.ExtractPorts(page)
+			if err != nil {
+				return err
+			}
+
+			for _, port := range pagePorts {
+				if port.Status != "ACTIVE" {
+					atomic.AddInt64(&lbPortsInactive, 1)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(exporter.Metrics["ports_lb_not_active"].Metric,
+		prometheus.GaugeValue, float64(atomic.LoadInt64(&lbPortsInactive)),
+		endpointOpts["network"].Region)
+
+	return nil
+}
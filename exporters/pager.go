@@ -0,0 +1,124 @@
+package exporters
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/gophercloud/gophercloud/pagination"
+)
+
+// scrapeConcurrency returns the configured worker-pool size used to fan out concurrent work
+// during a single collector's scrape. Defaults to runtime.NumCPU() when
+// ExporterConfig.ListConcurrency isn't set.
+func (e *BaseOpenStackExporter) scrapeConcurrency() int {
+	if e.ExporterConfig.ListConcurrency > 0 {
+		return e.ExporterConfig.ListConcurrency
+	}
+
+	return runtime.NumCPU()
+}
+
+// scrapePool is a semaphore shared by the per-item work (per-item emission, per-item follow-up
+// calls) that every page's handler fans out in a single list function's scrape, so that
+// nesting forEachConcurrent under eachPageConcurrent bounds concurrent item work at
+// scrapeConcurrency() instead of multiplying it once per page. Page fetching and the per-page
+// handler goroutines are not gated by it.
+type scrapePool struct {
+	sem chan struct{}
+}
+
+// newScrapePool returns a scrapePool sized to e.scrapeConcurrency().
+func (e *BaseOpenStackExporter) newScrapePool() *scrapePool {
+	return &scrapePool{sem: make(chan struct{}, e.scrapeConcurrency())}
+}
+
+func (p *scrapePool) acquire() { p.sem <- struct{}{} }
+func (p *scrapePool) release() { <-p.sem }
+
+// forEachConcurrent calls fn(i) for every i in [0, n) using pool, and returns the first error
+// encountered. List functions use this to fan out per-item metric emission (and, for routers,
+// the per-item ListL3Agents call) across a scrape instead of handling every item one at a time.
+// Pass the same pool a list function got from eachPageConcurrent when fanning out per-item work
+// within a single page's handler, so the two layers share one concurrency bound; pass a fresh
+// e.newScrapePool() when called on its own (i.e. not nested under eachPageConcurrent).
+func (e *BaseOpenStackExporter) forEachConcurrent(pool *scrapePool, n int, fn func(i int) error) error {
+	errs := make(chan error, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		pool.acquire()
+
+		go func(i int) {
+			defer wg.Done()
+			defer pool.release()
+
+			if err := fn(i); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// eachPageConcurrent drives pager with gophercloud's Pager.EachPage instead of AllPages(), so
+// a list function no longer has to wait for every page of a resource to be fetched before it
+// can start extracting and emitting metrics. Page N+1 is requested as soon as handlePage(N)
+// has been handed off to a goroutine, rather than after handlePage(N) finishes running.
+//
+// Spawning the per-page goroutine itself isn't gated by the pool: extracting a page is cheap,
+// in-memory work, not an API call, so letting it run eagerly is harmless. handlePage receives
+// the pool so that any actual concurrent work it fans out (via forEachConcurrent) draws from
+// the same scrapeConcurrency()-sized bound as every other page's handler in the scrape,
+// rather than each page creating its own pool and multiplying the configured concurrency.
+// Gating the goroutine spawn here too, on the same pool, would deadlock: every slot could end
+// up held by a page goroutine blocked trying to acquire a second slot for its own item fan-out.
+func (e *BaseOpenStackExporter) eachPageConcurrent(pager pagination.Pager, handlePage func(page pagination.Page, pool *scrapePool) error) error {
+	pool := e.newScrapePool()
+	errOnce := make(chan error, 1)
+
+	reportErr := func(err error) {
+		select {
+		case errOnce <- err:
+		default:
+		}
+	}
+
+	var wg sync.WaitGroup
+
+	_, err := pager.EachPage(func(page pagination.Page) (bool, error) {
+		wg.Add(1)
+
+		go func(page pagination.Page) {
+			defer wg.Done()
+
+			if err := handlePage(page, pool); err != nil {
+				reportErr(err)
+			}
+		}(page)
+
+		return true, nil
+	})
+	if err != nil {
+		reportErr(err)
+	}
+
+	wg.Wait()
+
+	select {
+	case err := <-errOnce:
+		return err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,162 @@
+package exporters
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/domains"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/projects"
+)
+
+// defaultProjectCacheTTL is used when ExporterConfig.ProjectCacheTTL isn't set.
+const defaultProjectCacheTTL = 10 * time.Minute
+
+// projectInfo is the cached subset of a Keystone project used to enrich metric labels.
+type projectInfo struct {
+	Name       string
+	DomainID   string
+	DomainName string
+	ParentID   string
+	Enabled    bool
+}
+
+// ProjectResolver caches the Keystone project_id -> {name, domain} mapping on a TTL so that
+// exporters can attach project_name/domain_name labels without a Keystone round-trip per
+// metric. It is held on BaseOpenStackExporter and shared across exporters (Neutron, Nova,
+// Cinder, ...) so the Keystone calls needed to build the cache are only paid for once per
+// TTL, not once per exporter.
+type ProjectResolver struct {
+	client *gophercloud.ServiceClient
+	ttl    time.Duration
+
+	mu          sync.RWMutex
+	projects    map[string]projectInfo
+	lastRefresh time.Time
+}
+
+// sharedProjectResolvers holds the one ProjectResolver built per Identity v3 client.
+var (
+	sharedProjectResolversMu sync.Mutex
+	sharedProjectResolvers   = map[*gophercloud.ServiceClient]*ProjectResolver{}
+)
+
+// NewProjectResolver : returns a pointer to the ProjectResolver backed by the given Identity v3
+// client. Every call with the same client returns the same resolver, so exporters share one
+// cache; the ttl of the first call for that client is the one that applies.
+func NewProjectResolver(client *gophercloud.ServiceClient, ttl time.Duration) *ProjectResolver {
+	sharedProjectResolversMu.Lock()
+	defer sharedProjectResolversMu.Unlock()
+
+	if resolver, ok := sharedProjectResolvers[client]; ok {
+		return resolver
+	}
+
+	resolver := &ProjectResolver{
+		client:   client,
+		ttl:      ttl,
+		projects: map[string]projectInfo{},
+	}
+	sharedProjectResolvers[client] = resolver
+
+	return resolver
+}
+
+// ProjectLabels returns the project_name and domain_name for a project ID, refreshing the
+// cache first if its TTL has expired. It falls back to the raw project ID, and an empty
+// domain_name, when the project can't be resolved so callers can always use the result
+// directly as label values.
+func (r *ProjectResolver) ProjectLabels(projectID string) (projectName string, domainName string) {
+	if projectID == "" {
+		return "", ""
+	}
+
+	if err := r.refreshIfStale(); err != nil {
+		return projectID, ""
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	info, ok := r.projects[projectID]
+	if !ok {
+		return projectID, ""
+	}
+
+	return info.Name, info.DomainName
+}
+
+func (r *ProjectResolver) refreshIfStale() error {
+	r.mu.RLock()
+	stale := time.Since(r.lastRefresh) > r.ttl
+	r.mu.RUnlock()
+
+	if !stale {
+		return nil
+	}
+
+	return r.refresh()
+}
+
+// refresh lists Keystone domains and projects and rebuilds the cache from scratch.
+func (r *ProjectResolver) refresh() error {
+	domainNames := map[string]string{}
+
+	allPagesDomains, err := domains.List(r.client, domains.ListOpts{}).AllPages()
+	if err != nil {
+		return err
+	}
+
+	allDomains, err := domains.ExtractDomains(allPagesDomains)
+	if err != nil {
+		return err
+	}
+
+	for _, domain := range allDomains {
+		domainNames[domain.ID] = domain.Name
+	}
+
+	allPagesProjects, err := projects.List(r.client, projects.ListOpts{}).AllPages()
+	if err != nil {
+		return err
+	}
+
+	allProjects, err := projects.ExtractProjects(allPagesProjects)
+	if err != nil {
+		return err
+	}
+
+	cache := make(map[string]projectInfo, len(allProjects))
+	for _, project := range allProjects {
+		cache[project.ID] = projectInfo{
+			Name:       project.Name,
+			DomainID:   project.DomainID,
+			DomainName: domainNames[project.DomainID],
+			ParentID:   project.ParentID,
+			Enabled:    project.Enabled,
+		}
+	}
+
+	r.mu.Lock()
+	r.projects = cache
+	r.lastRefresh = time.Now()
+	r.mu.Unlock()
+
+	return nil
+}
+
+// projectLabelValues returns the {project_name, domain_name} label pair for projectID when
+// EnableProjectLabels is set, or nil otherwise so callers can safely append() the result
+// regardless of configuration.
+func (e *BaseOpenStackExporter) projectLabelValues(projectID string) []string {
+	if !e.ExporterConfig.EnableProjectLabels {
+		return nil
+	}
+
+	projectName, domainName := projectID, ""
+	if e.ProjectResolver != nil {
+		projectName, domainName = e.ProjectResolver.ProjectLabels(projectID)
+	}
+
+	return []string{projectName, domainName}
+}
@@ -0,0 +1,66 @@
+package exporters
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var defaultScrapeTimeoutMetrics = []Metric{
+	{Name: "scrape_duration_seconds", Labels: []string{"collector"}},
+	{Name: "scrape_error", Labels: []string{"collector"}},
+}
+
+// Collect : delegates to BaseOpenStackExporter.Collect on a background goroutine, aborting
+// the scrape and reporting partial metrics once ExporterConfig.ScrapeTimeout elapses, so a
+// slow Neutron can't stall the whole /metrics endpoint. The background goroutine collects into
+// a buffered channel it owns exclusively; this function is the only goroutine that ever writes
+// to ch, so an abandoned collect can never race a closed/reused channel. Always reports its own
+// duration and whether it was aborted via scrape_duration_seconds/scrape_error.
+func (exporter *NeutronExporter) Collect(ch chan<- prometheus.Metric) {
+	start := time.Now()
+
+	ctx := context.Background()
+	if exporter.ExporterConfig.ScrapeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, exporter.ExporterConfig.ScrapeTimeout)
+		defer cancel()
+	}
+
+	collected := make(chan prometheus.Metric, 4096)
+	go func() {
+		defer close(collected)
+		exporter.BaseOpenStackExporter.Collect(collected)
+	}()
+
+	aborted := float64(0)
+drain:
+	for {
+		select {
+		case metric, ok := <-collected:
+			if !ok {
+				break drain
+			}
+			ch <- metric
+		case <-ctx.Done():
+			aborted = 1
+			break drain
+		}
+	}
+
+	// If we broke out on the timeout, the background goroutine may still be sending to
+	// collected; keep draining it in the background (instead of gating on it finishing
+	// first) so it can't block forever on a full buffer waiting for a reader.
+	if aborted == 1 {
+		go func() {
+			for range collected {
+			}
+		}()
+	}
+
+	ch <- prometheus.MustNewConstMetric(exporter.Metrics["scrape_duration_seconds"].Metric,
+		prometheus.GaugeValue, time.Since(start).Seconds(), exporter.Name)
+	ch <- prometheus.MustNewConstMetric(exporter.Metrics["scrape_error"].Metric,
+		prometheus.GaugeValue, aborted, exporter.Name)
+}